@@ -4,6 +4,8 @@
 package types
 
 import (
+	"encoding/json"
+	"fmt"
 	"net"
 	"net/netip"
 	"strconv"
@@ -19,8 +21,16 @@ type IdentityUpdater interface {
 }
 
 // ResourceID identifies a unique copy of a resource that provides a source for
-// information tied to an IP address in the IPCache.
-type ResourceID string
+// information tied to an IP address in the IPCache. It is used as a map key
+// in hot paths of the IPCache metadata layer, so the joined string form is
+// kept alongside the byte offsets of its two separators: Kind, Namespace and
+// Name can then be sliced out directly instead of re-splitting on every
+// call.
+type ResourceID struct {
+	str  string
+	sep1 uint16 // index of the separator between kind and namespace
+	sep2 uint16 // index of the separator between namespace and name
+}
 
 // ResourceKind determines the source of the ResourceID. Typically this is the
 // short name for the k8s resource.
@@ -35,27 +45,135 @@ var (
 	ResourceKindFile      = ResourceKind("file")
 	ResourceKindNetpol    = ResourceKind("netpol")
 	ResourceKindNode      = ResourceKind("node")
+	ResourceKindTunnel    = ResourceKind("tunnel")
 )
 
+// knownResourceKinds is the registry of ResourceKind values recognized by
+// this package. It backs ResourceKind.Validate() so that unknown kinds can
+// be rejected at ingestion rather than silently propagated.
+var knownResourceKinds = map[ResourceKind]struct{}{
+	ResourceKindCCNP:      {},
+	ResourceKindCIDRGroup: {},
+	ResourceKindCNP:       {},
+	ResourceKindDaemon:    {},
+	ResourceKindEndpoint:  {},
+	ResourceKindFile:      {},
+	ResourceKindNetpol:    {},
+	ResourceKindNode:      {},
+	ResourceKindTunnel:    {},
+}
+
+// Validate returns an error if k is not one of the registered
+// ResourceKind values.
+func (k ResourceKind) Validate() error {
+	if _, ok := knownResourceKinds[k]; !ok {
+		return fmt.Errorf("unknown resource kind %q", string(k))
+	}
+	return nil
+}
+
 // NewResourceID returns a ResourceID populated with the standard fields for
 // uniquely identifying a source of IPCache information.
 func NewResourceID(kind ResourceKind, namespace, name string) ResourceID {
 	str := strings.Builder{}
 	str.Grow(len(kind) + 1 + len(namespace) + 1 + len(name))
 	str.WriteString(string(kind))
+	sep1 := str.Len()
 	str.WriteRune('/')
 	str.WriteString(namespace)
+	sep2 := str.Len()
 	str.WriteRune('/')
 	str.WriteString(name)
-	return ResourceID(str.String())
+	return ResourceID{str: str.String(), sep1: uint16(sep1), sep2: uint16(sep2)}
+}
+
+// ParseResourceID parses the canonical "kind/namespace/name" form of a
+// ResourceID, as produced by String(). It requires exactly two separators,
+// a non-empty kind and a non-empty name; namespace may be empty for
+// cluster-scoped resources such as ResourceKindNode or ResourceKindDaemon.
+// It returns ok=false if s is malformed.
+func ParseResourceID(s string) (ResourceID, bool) {
+	sep1 := strings.IndexByte(s, '/')
+	if sep1 <= 0 {
+		return ResourceID{}, false
+	}
+	rest := s[sep1+1:]
+	sep2Rel := strings.IndexByte(rest, '/')
+	if sep2Rel < 0 {
+		return ResourceID{}, false
+	}
+	sep2 := sep1 + 1 + sep2Rel
+	if sep2 >= len(s)-1 {
+		return ResourceID{}, false
+	}
+	if strings.IndexByte(s[sep2+1:], '/') >= 0 {
+		return ResourceID{}, false
+	}
+	return ResourceID{str: s, sep1: uint16(sep1), sep2: uint16(sep2)}, true
+}
+
+// String returns the canonical "kind/namespace/name" form of the
+// ResourceID, preserving the existing on-the-wire and log format.
+func (r ResourceID) String() string {
+	return r.str
 }
 
+// MarshalJSON encodes r as a JSON string in its canonical form, matching
+// the encoding ResourceID had before it gained cached separator offsets.
+func (r ResourceID) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.str)
+}
+
+// UnmarshalJSON decodes r from the canonical "kind/namespace/name" form.
+func (r *ResourceID) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+	parsed, ok := ParseResourceID(str)
+	if !ok {
+		return fmt.Errorf("malformed resource ID %q", str)
+	}
+	*r = parsed
+	return nil
+}
+
+// Kind returns the ResourceKind component of r, or "" for the zero value.
+// It does not validate r; use Parse() for that.
+func (r ResourceID) Kind() ResourceKind {
+	if r.str == "" {
+		return ""
+	}
+	return ResourceKind(r.str[:r.sep1])
+}
+
+// Namespace returns the namespace component of r. It is "" both for
+// cluster-scoped resources and for the zero value; use Parse() to tell the
+// two apart.
 func (r ResourceID) Namespace() string {
-	parts := strings.SplitN(string(r), "/", 3)
-	if len(parts) < 2 {
+	if r.str == "" {
+		return ""
+	}
+	return r.str[r.sep1+1 : r.sep2]
+}
+
+// Name returns the name component of r, or "" for the zero value.
+func (r ResourceID) Name() string {
+	if r.str == "" {
 		return ""
 	}
-	return parts[1]
+	return r.str[r.sep2+1:]
+}
+
+// Parse splits r into its kind, namespace and name components. ok is false
+// if r is the zero value or is not in the form ParseResourceID accepts
+// (exactly two separators, a non-empty kind and a non-empty name), in which
+// case the other return values are "".
+func (r ResourceID) Parse() (kind ResourceKind, namespace, name string, ok bool) {
+	if _, valid := ParseResourceID(r.str); !valid {
+		return "", "", "", false
+	}
+	return r.Kind(), r.Namespace(), r.Name(), true
 }
 
 // TunnelPeer is the IP address of the host associated with this prefix. This is
@@ -67,22 +185,77 @@ func (t TunnelPeer) IP() net.IP {
 	return t.AsSlice()
 }
 
-// EncryptKey is the identity of the encryption key.
+// EncryptKey is the identity of the encryption key in use for a prefix. It
+// packs two 4-bit key IDs into a single byte: the low nibble is the
+// "current" key, used for decryption today, and the high nibble is the
+// "pending" key, accepted (but not yet preferred) during a rolling key
+// rotation. Keeping both in one value lets a prefix carry the rotation
+// state atomically instead of forcing a global cutover between the old and
+// new key.
 // This type implements ipcache.IPMetadata
 type EncryptKey uint8
 
 const EncryptKeyEmpty = EncryptKey(0)
 
+// maxEncryptKeyID is one past the highest key ID that fits in a nibble,
+// preserving the existing 16-key limit.
+const maxEncryptKeyID = 16
+
+// NewEncryptKey returns an EncryptKey with the given current and pending key
+// IDs. It returns an error if either ID does not fit in the 16-key limit.
+func NewEncryptKey(current, pending uint8) (EncryptKey, error) {
+	if current >= maxEncryptKeyID || pending >= maxEncryptKeyID {
+		return EncryptKeyEmpty, fmt.Errorf("encrypt key ID must be in [0, %d), got current=%d pending=%d", maxEncryptKeyID, current, pending)
+	}
+	return EncryptKey(current | pending<<4), nil
+}
+
+// IsValid returns true if either the current or the pending key is set.
 func (e EncryptKey) IsValid() bool {
-	return e != EncryptKeyEmpty
+	return e.CurrentKeyID() != 0 || e.PendingKeyID() != 0
+}
+
+// CurrentKeyID returns the key ID currently in use for decryption.
+func (e EncryptKey) CurrentKeyID() uint8 {
+	return uint8(e) & 0xf
+}
+
+// PendingKeyID returns the key ID the datapath should additionally accept
+// during a rotation window, or 0 if no rotation is in progress.
+func (e EncryptKey) PendingKeyID() uint8 {
+	return uint8(e) >> 4
+}
+
+// WithPending returns a copy of e with its pending key ID set, leaving the
+// current key ID untouched. The pending ID is masked to its low nibble.
+func (e EncryptKey) WithPending(pending uint8) EncryptKey {
+	return EncryptKey(e.CurrentKeyID() | (pending&0xf)<<4)
 }
 
+// Promote returns a copy of e with the pending key ID moved into the
+// current slot and the pending slot cleared. This is the second half of a
+// rolling key rotation, once all nodes accept the new key.
+func (e EncryptKey) Promote() EncryptKey {
+	return EncryptKey(e.PendingKeyID())
+}
+
+// Uint8 returns the current key ID. The encoding MUST stay bit-compatible
+// with pkg/maps/ipcache, which only ever programs the current key.
 func (e EncryptKey) Uint8() uint8 {
-	return uint8(e)
+	return e.CurrentKeyID()
+}
+
+// Uint8Pending returns the pending key ID, for consumers that want to
+// program a second security association during a rotation window.
+func (e EncryptKey) Uint8Pending() uint8 {
+	return e.PendingKeyID()
 }
 
 func (e EncryptKey) String() string {
-	return strconv.Itoa(int(e))
+	if pending := e.PendingKeyID(); pending != 0 {
+		return strconv.Itoa(int(e.CurrentKeyID())) + "->" + strconv.Itoa(int(pending))
+	}
+	return strconv.Itoa(int(e.CurrentKeyID()))
 }
 
 // RequestedIdentity is a desired numeric identity for the prefix. When the
@@ -107,36 +280,210 @@ func (id RequestedIdentity) ID() identity.NumericIdentity {
 // EndpointFlags represents various flags that can be attached to endpoints in the IPCache
 // This type implements ipcache.IPMetadata
 type EndpointFlags struct {
-	// isInit gets flipped to true on the first intentional flag set
+	// initialized gets flipped to true on the first intentional flag set
 	// it is a sentinel to distinguish an uninitialized EndpointFlags
 	// from one with all flags set to false
-	isInit bool
+	initialized bool
 
-	// flagSkipTunnel can be applied to a remote endpoint to signal that
-	// packets destined for said endpoint shall not be forwarded through
-	// an overlay tunnel, regardless of Cilium's configuration.
-	flagSkipTunnel bool
+	// bits is a bitmask of the flagBit* values below.
+	bits uint16
 }
 
+// flagBit* enumerates the individual bits packed into EndpointFlags.bits.
+// The low byte MUST mimic the Uint8 encoding in pkg/maps/ipcache since it
+// will eventually get recast to it, so only flagBitSkipTunnel (the one flag
+// that map already knows about) lives there; flags that don't need to
+// reach the datapath via that map are allocated in the high byte instead.
+const (
+	flagBitSkipTunnel uint16 = 1 << iota
+	_                        // reserved: low byte is reserved for pkg/maps/ipcache parity
+	_
+	_
+	_
+	_
+	_
+	_
+	flagBitSkipEncryption
+	flagBitNoAdvertise
+	flagBitForceEncap
+)
+
+// FlagSkipTunnel is the Uint8-compatible encoding of flagBitSkipTunnel,
+// preserved for consumers that still operate on the narrower encoding.
+const FlagSkipTunnel uint8 = uint8(flagBitSkipTunnel)
+
+func (e *EndpointFlags) set(bit uint16, value bool) {
+	e.initialized = true
+	if value {
+		e.bits |= bit
+	} else {
+		e.bits &^= bit
+	}
+}
+
+// SetSkipTunnel signals that packets destined for this prefix shall not be
+// forwarded through an overlay tunnel, regardless of Cilium's configuration.
 func (e *EndpointFlags) SetSkipTunnel(skip bool) {
-	e.isInit = true
-	e.flagSkipTunnel = skip
+	e.set(flagBitSkipTunnel, skip)
+}
+
+func (e EndpointFlags) IsSkipTunnel() bool {
+	return e.bits&flagBitSkipTunnel != 0
+}
+
+// SetSkipEncryption signals that the datapath shall bypass IPsec/WireGuard
+// encryption for this prefix, e.g. during an IPsec dry-run.
+func (e *EndpointFlags) SetSkipEncryption(skip bool) {
+	e.set(flagBitSkipEncryption, skip)
+}
+
+func (e EndpointFlags) IsSkipEncryption() bool {
+	return e.bits&flagBitSkipEncryption != 0
+}
+
+// SetNoAdvertise suppresses BGP/route advertisement of this prefix, e.g.
+// for egress-gateway prefixes that must stay off the advertised routes.
+func (e *EndpointFlags) SetNoAdvertise(noAdvertise bool) {
+	e.set(flagBitNoAdvertise, noAdvertise)
+}
+
+func (e EndpointFlags) IsNoAdvertise() bool {
+	return e.bits&flagBitNoAdvertise != 0
+}
+
+// SetForceEncap forces overlay encapsulation for this prefix even when
+// native routing would otherwise apply.
+func (e *EndpointFlags) SetForceEncap(force bool) {
+	e.set(flagBitForceEncap, force)
+}
+
+func (e EndpointFlags) IsForceEncap() bool {
+	return e.bits&flagBitForceEncap != 0
 }
 
 func (e EndpointFlags) IsValid() bool {
-	return e.isInit
+	return e.initialized
 }
 
-// Uint8 encoding MUST mimic the one in pkg/maps/ipcache
-// since it will eventually get recast to it
-const (
-	FlagSkipTunnel uint8 = 1 << iota
-)
+// Merge returns a copy of e with other's flags ORed in. initialized
+// propagates if either side is initialized, so a merge of an uninitialized
+// EndpointFlags with an initialized one is itself initialized.
+func (e EndpointFlags) Merge(other EndpointFlags) EndpointFlags {
+	return EndpointFlags{
+		initialized: e.initialized || other.initialized,
+		bits:        e.bits | other.bits,
+	}
+}
 
+// Uint8 encoding MUST mimic the one in pkg/maps/ipcache since it will
+// eventually get recast to it. Only the low byte of Uint16 is representable
+// here; flags added above the low byte are not visible through Uint8.
 func (e EndpointFlags) Uint8() uint8 {
-	var flags uint8 = 0
-	if e.flagSkipTunnel {
-		flags = flags | FlagSkipTunnel
+	return uint8(e.bits)
+}
+
+// Uint16 returns the full flag bitmask, including flags that don't fit in
+// the Uint8 encoding.
+func (e EndpointFlags) Uint16() uint16 {
+	return e.bits
+}
+
+// String returns a stable, comma-separated list of the flags that are set,
+// suitable for debug dumps and Hubble enrichment.
+func (e EndpointFlags) String() string {
+	if !e.initialized || e.bits == 0 {
+		return ""
+	}
+	var flags []string
+	if e.IsSkipTunnel() {
+		flags = append(flags, "skip-tunnel")
+	}
+	if e.IsSkipEncryption() {
+		flags = append(flags, "skip-encryption")
 	}
-	return flags
+	if e.IsNoAdvertise() {
+		flags = append(flags, "no-advertise")
+	}
+	if e.IsForceEncap() {
+		flags = append(flags, "force-encap")
+	}
+	return strings.Join(flags, ",")
+}
+
+// TunnelEndpointInfo bundles the tunnel peer, encryption key and endpoint
+// flags for a prefix into a single value. These three were previously
+// injected into the IPCache as independent metadata from independent
+// resource IDs, which let the datapath tunnel map observe them out of
+// sync, e.g. a new tunnel endpoint paired with a stale encryption key.
+// Bundling them lets a single resource (ResourceKindTunnel) own all three
+// atomically.
+// This type implements ipcache.IPMetadata
+type TunnelEndpointInfo struct {
+	tunnelPeer TunnelPeer
+	key        EncryptKey
+	flags      EndpointFlags
+}
+
+// NewTunnelEndpointInfo builds a TunnelEndpointInfo from the three
+// standalone metadata types, for callers migrating incrementally.
+func NewTunnelEndpointInfo(peer TunnelPeer, key EncryptKey, flags EndpointFlags) TunnelEndpointInfo {
+	return TunnelEndpointInfo{
+		tunnelPeer: peer,
+		key:        key,
+		flags:      flags,
+	}
+}
+
+func (t TunnelEndpointInfo) TunnelEndpoint() netip.Addr {
+	return t.tunnelPeer.Addr
+}
+
+func (t TunnelEndpointInfo) Key() EncryptKey {
+	return t.key
+}
+
+func (t TunnelEndpointInfo) Flags() EndpointFlags {
+	return t.flags
+}
+
+// ToTunnelPeer, ToEncryptKey and ToEndpointFlags unbundle a
+// TunnelEndpointInfo back into the three standalone metadata types, for
+// callers that have not yet migrated to the composite type.
+func (t TunnelEndpointInfo) ToTunnelPeer() TunnelPeer {
+	return t.tunnelPeer
+}
+
+func (t TunnelEndpointInfo) ToEncryptKey() EncryptKey {
+	return t.key
+}
+
+func (t TunnelEndpointInfo) ToEndpointFlags() EndpointFlags {
+	return t.flags
+}
+
+func (t TunnelEndpointInfo) IsValid() bool {
+	return t.tunnelPeer.Addr.IsValid() || t.key.IsValid() || t.flags.IsValid()
+}
+
+// Merge returns a copy of t with each field replaced by other's, if other
+// carries a non-zero value for that field. This picks the highest-priority
+// non-zero field across the two sources rather than ORing them together.
+func (t TunnelEndpointInfo) Merge(other TunnelEndpointInfo) TunnelEndpointInfo {
+	result := t
+	if other.tunnelPeer.Addr.IsValid() {
+		result.tunnelPeer = other.tunnelPeer
+	}
+	if other.key.IsValid() {
+		result.key = other.key
+	}
+	if other.flags.IsValid() {
+		result.flags = other.flags
+	}
+	return result
+}
+
+// Equal returns true if t and other carry the same tunnel peer, key and
+// flags, allowing the IPCache to short-circuit redundant updates.
+func (t TunnelEndpointInfo) Equal(other TunnelEndpointInfo) bool {
+	return t.tunnelPeer.Addr == other.tunnelPeer.Addr && t.key == other.key && t.flags == other.flags
 }
@@ -0,0 +1,415 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package types
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestEncryptKeyRoundTrip(t *testing.T) {
+	for current := uint8(0); current < maxEncryptKeyID; current++ {
+		for pending := uint8(0); pending < maxEncryptKeyID; pending++ {
+			key, err := NewEncryptKey(current, pending)
+			if err != nil {
+				t.Fatalf("NewEncryptKey(%d, %d) returned unexpected error: %v", current, pending, err)
+			}
+			if got := key.CurrentKeyID(); got != current {
+				t.Errorf("NewEncryptKey(%d, %d).CurrentKeyID() = %d, want %d", current, pending, got, current)
+			}
+			if got := key.PendingKeyID(); got != pending {
+				t.Errorf("NewEncryptKey(%d, %d).PendingKeyID() = %d, want %d", current, pending, got, pending)
+			}
+		}
+	}
+}
+
+func TestNewEncryptKeyRejectsOutOfRange(t *testing.T) {
+	cases := []struct {
+		current, pending uint8
+	}{
+		{maxEncryptKeyID, 0},
+		{0, maxEncryptKeyID},
+		{255, 255},
+	}
+	for _, tc := range cases {
+		if _, err := NewEncryptKey(tc.current, tc.pending); err == nil {
+			t.Errorf("NewEncryptKey(%d, %d) = nil error, want error", tc.current, tc.pending)
+		}
+	}
+}
+
+func TestEncryptKeyWithPending(t *testing.T) {
+	key, err := NewEncryptKey(3, 0)
+	if err != nil {
+		t.Fatalf("NewEncryptKey(3, 0) returned unexpected error: %v", err)
+	}
+
+	key = key.WithPending(7)
+	if got := key.CurrentKeyID(); got != 3 {
+		t.Errorf("after WithPending, CurrentKeyID() = %d, want 3", got)
+	}
+	if got := key.PendingKeyID(); got != 7 {
+		t.Errorf("after WithPending, PendingKeyID() = %d, want 7", got)
+	}
+}
+
+func TestEncryptKeyPromote(t *testing.T) {
+	key, err := NewEncryptKey(3, 7)
+	if err != nil {
+		t.Fatalf("NewEncryptKey(3, 7) returned unexpected error: %v", err)
+	}
+
+	promoted := key.Promote()
+	if got := promoted.CurrentKeyID(); got != 7 {
+		t.Errorf("after Promote, CurrentKeyID() = %d, want 7", got)
+	}
+	if got := promoted.PendingKeyID(); got != 0 {
+		t.Errorf("after Promote, PendingKeyID() = %d, want 0", got)
+	}
+}
+
+func TestEncryptKeyIsValid(t *testing.T) {
+	if EncryptKeyEmpty.IsValid() {
+		t.Error("EncryptKeyEmpty.IsValid() = true, want false")
+	}
+
+	current, err := NewEncryptKey(1, 0)
+	if err != nil {
+		t.Fatalf("NewEncryptKey(1, 0) returned unexpected error: %v", err)
+	}
+	if !current.IsValid() {
+		t.Error("key with only current set: IsValid() = false, want true")
+	}
+
+	pendingOnly, err := NewEncryptKey(0, 1)
+	if err != nil {
+		t.Fatalf("NewEncryptKey(0, 1) returned unexpected error: %v", err)
+	}
+	if !pendingOnly.IsValid() {
+		t.Error("key with only pending set: IsValid() = false, want true")
+	}
+}
+
+// TestEncryptKeyUint8Alignment locks in the on-wire layout that
+// pkg/maps/ipcache depends on: Uint8() must return only the current key ID,
+// confined to the low nibble, regardless of what the pending key ID is.
+func TestEncryptKeyUint8Alignment(t *testing.T) {
+	for current := uint8(0); current < maxEncryptKeyID; current++ {
+		for pending := uint8(0); pending < maxEncryptKeyID; pending++ {
+			key, err := NewEncryptKey(current, pending)
+			if err != nil {
+				t.Fatalf("NewEncryptKey(%d, %d) returned unexpected error: %v", current, pending, err)
+			}
+			if got := key.Uint8(); got != current {
+				t.Errorf("NewEncryptKey(%d, %d).Uint8() = %d, want %d", current, pending, got, current)
+			}
+			if got := key.Uint8() & 0xf0; got != 0 {
+				t.Errorf("NewEncryptKey(%d, %d).Uint8() = 0x%x, low nibble must not bleed into the high nibble", current, pending, key.Uint8())
+			}
+			if got := key.Uint8Pending(); got != pending {
+				t.Errorf("NewEncryptKey(%d, %d).Uint8Pending() = %d, want %d", current, pending, got, pending)
+			}
+		}
+	}
+}
+
+// Note: asserting that a single Upsert with TunnelEndpointInfo produces
+// exactly one datapath notification requires the IPCache metadata layer,
+// which does not exist in this tree; the TunnelEndpointInfo behavior it
+// depends on (Merge/Equal/conversion) is covered here instead.
+
+func mustAddr(t *testing.T, s string) netip.Addr {
+	t.Helper()
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		t.Fatalf("netip.ParseAddr(%q) returned unexpected error: %v", s, err)
+	}
+	return addr
+}
+
+func TestTunnelEndpointInfoConversionHelpers(t *testing.T) {
+	peer := TunnelPeer{mustAddr(t, "10.0.0.1")}
+	key, err := NewEncryptKey(3, 0)
+	if err != nil {
+		t.Fatalf("NewEncryptKey(3, 0) returned unexpected error: %v", err)
+	}
+	var flags EndpointFlags
+	flags.SetSkipTunnel(true)
+
+	info := NewTunnelEndpointInfo(peer, key, flags)
+
+	if got := info.TunnelEndpoint(); got != peer.Addr {
+		t.Errorf("TunnelEndpoint() = %v, want %v", got, peer.Addr)
+	}
+	if got := info.Key(); got != key {
+		t.Errorf("Key() = %v, want %v", got, key)
+	}
+	if got := info.Flags(); got != flags {
+		t.Errorf("Flags() = %v, want %v", got, flags)
+	}
+	if got := info.ToTunnelPeer(); got != peer {
+		t.Errorf("ToTunnelPeer() = %v, want %v", got, peer)
+	}
+	if got := info.ToEncryptKey(); got != key {
+		t.Errorf("ToEncryptKey() = %v, want %v", got, key)
+	}
+	if got := info.ToEndpointFlags(); got != flags {
+		t.Errorf("ToEndpointFlags() = %v, want %v", got, flags)
+	}
+}
+
+func TestTunnelEndpointInfoIsValid(t *testing.T) {
+	var empty TunnelEndpointInfo
+	if empty.IsValid() {
+		t.Error("zero-value TunnelEndpointInfo.IsValid() = true, want false")
+	}
+
+	withPeer := NewTunnelEndpointInfo(TunnelPeer{mustAddr(t, "10.0.0.1")}, EncryptKeyEmpty, EndpointFlags{})
+	if !withPeer.IsValid() {
+		t.Error("TunnelEndpointInfo with only a tunnel peer: IsValid() = false, want true")
+	}
+}
+
+func TestTunnelEndpointInfoMerge(t *testing.T) {
+	keyA, err := NewEncryptKey(1, 0)
+	if err != nil {
+		t.Fatalf("NewEncryptKey(1, 0) returned unexpected error: %v", err)
+	}
+	keyB, err := NewEncryptKey(2, 0)
+	if err != nil {
+		t.Fatalf("NewEncryptKey(2, 0) returned unexpected error: %v", err)
+	}
+
+	base := NewTunnelEndpointInfo(TunnelPeer{mustAddr(t, "10.0.0.1")}, keyA, EndpointFlags{})
+
+	// other carries no fields: base is unchanged.
+	merged := base.Merge(TunnelEndpointInfo{})
+	if !merged.Equal(base) {
+		t.Errorf("Merge with empty other = %v, want unchanged %v", merged, base)
+	}
+
+	// other overrides the key and flags but not the tunnel peer, which it
+	// leaves unset.
+	var otherFlags EndpointFlags
+	otherFlags.SetSkipTunnel(true)
+	other := NewTunnelEndpointInfo(TunnelPeer{}, keyB, otherFlags)
+	merged = base.Merge(other)
+	if got := merged.TunnelEndpoint(); got != base.TunnelEndpoint() {
+		t.Errorf("Merge() tunnel peer = %v, want unchanged %v", got, base.TunnelEndpoint())
+	}
+	if got := merged.Key(); got != keyB {
+		t.Errorf("Merge() key = %v, want %v", got, keyB)
+	}
+	if got := merged.Flags(); got != otherFlags {
+		t.Errorf("Merge() flags = %v, want %v", got, otherFlags)
+	}
+}
+
+func TestTunnelEndpointInfoEqual(t *testing.T) {
+	key, err := NewEncryptKey(1, 0)
+	if err != nil {
+		t.Fatalf("NewEncryptKey(1, 0) returned unexpected error: %v", err)
+	}
+	a := NewTunnelEndpointInfo(TunnelPeer{mustAddr(t, "10.0.0.1")}, key, EndpointFlags{})
+	b := NewTunnelEndpointInfo(TunnelPeer{mustAddr(t, "10.0.0.1")}, key, EndpointFlags{})
+	c := NewTunnelEndpointInfo(TunnelPeer{mustAddr(t, "10.0.0.2")}, key, EndpointFlags{})
+
+	if !a.Equal(b) {
+		t.Error("a.Equal(b) = false, want true for identical TunnelEndpointInfo values")
+	}
+	if a.Equal(c) {
+		t.Error("a.Equal(c) = true, want false for differing tunnel peers")
+	}
+}
+
+// TestEndpointFlagsBitAlignment locks in the bit positions that
+// pkg/maps/ipcache depends on: flagBitSkipTunnel must stay at bit 0 of the
+// low byte, and the newer flags must stay above the low byte so they don't
+// leak into Uint8() and get misread by that map.
+func TestEndpointFlagsBitAlignment(t *testing.T) {
+	cases := []struct {
+		name string
+		bit  uint16
+		want uint16
+	}{
+		{"flagBitSkipTunnel", flagBitSkipTunnel, 1 << 0},
+		{"flagBitSkipEncryption", flagBitSkipEncryption, 1 << 8},
+		{"flagBitNoAdvertise", flagBitNoAdvertise, 1 << 9},
+		{"flagBitForceEncap", flagBitForceEncap, 1 << 10},
+	}
+	for _, tc := range cases {
+		if tc.bit != tc.want {
+			t.Errorf("%s = 0x%x, want 0x%x", tc.name, tc.bit, tc.want)
+		}
+	}
+
+	if FlagSkipTunnel != 1<<0 {
+		t.Errorf("FlagSkipTunnel = 0x%x, want 0x%x", FlagSkipTunnel, 1<<0)
+	}
+}
+
+func TestEndpointFlagsUint8OnlyExposesLowByte(t *testing.T) {
+	var flags EndpointFlags
+	flags.SetSkipEncryption(true)
+	flags.SetNoAdvertise(true)
+	flags.SetForceEncap(true)
+
+	if got := flags.Uint8(); got != 0 {
+		t.Errorf("Uint8() with only high-byte flags set = 0x%x, want 0", got)
+	}
+
+	flags.SetSkipTunnel(true)
+	if got := flags.Uint8(); got != FlagSkipTunnel {
+		t.Errorf("Uint8() with SkipTunnel also set = 0x%x, want 0x%x", got, FlagSkipTunnel)
+	}
+
+	if got := flags.Uint16(); got&0xff00 == 0 {
+		t.Error("Uint16() lost the high-byte flags that Uint8() cannot carry")
+	}
+}
+
+func TestEndpointFlagsMerge(t *testing.T) {
+	var a, b EndpointFlags
+	a.SetSkipTunnel(true)
+	b.SetNoAdvertise(true)
+
+	merged := a.Merge(b)
+	if !merged.IsSkipTunnel() {
+		t.Error("Merge() lost SkipTunnel from a")
+	}
+	if !merged.IsNoAdvertise() {
+		t.Error("Merge() lost NoAdvertise from b")
+	}
+	if !merged.IsValid() {
+		t.Error("Merge() of two initialized EndpointFlags: IsValid() = false, want true")
+	}
+
+	var uninitialized EndpointFlags
+	merged = uninitialized.Merge(a)
+	if !merged.IsValid() {
+		t.Error("Merge() of uninitialized with initialized: IsValid() = false, want true")
+	}
+}
+
+func TestEndpointFlagsString(t *testing.T) {
+	var flags EndpointFlags
+	if got := flags.String(); got != "" {
+		t.Errorf("String() on uninitialized EndpointFlags = %q, want \"\"", got)
+	}
+
+	flags.SetSkipTunnel(true)
+	flags.SetForceEncap(true)
+	if got, want := flags.String(), "skip-tunnel,force-encap"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestResourceIDAccessors(t *testing.T) {
+	id := NewResourceID(ResourceKindCNP, "kube-system", "my-policy")
+
+	if got, want := id.Kind(), ResourceKindCNP; got != want {
+		t.Errorf("Kind() = %q, want %q", got, want)
+	}
+	if got, want := id.Namespace(), "kube-system"; got != want {
+		t.Errorf("Namespace() = %q, want %q", got, want)
+	}
+	if got, want := id.Name(), "my-policy"; got != want {
+		t.Errorf("Name() = %q, want %q", got, want)
+	}
+	if got, want := id.String(), "cnp/kube-system/my-policy"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	kind, namespace, name, ok := id.Parse()
+	if !ok {
+		t.Fatal("Parse() ok = false, want true for a well-formed ResourceID")
+	}
+	if kind != ResourceKindCNP || namespace != "kube-system" || name != "my-policy" {
+		t.Errorf("Parse() = (%q, %q, %q), want (%q, %q, %q)", kind, namespace, name, ResourceKindCNP, "kube-system", "my-policy")
+	}
+}
+
+func TestResourceIDClusterScoped(t *testing.T) {
+	// Cluster-scoped resources (e.g. nodes, the daemon itself) have no
+	// namespace, which must be distinguishable from malformed input.
+	id := NewResourceID(ResourceKindNode, "", "node1")
+
+	if got, want := id.Namespace(), ""; got != want {
+		t.Errorf("Namespace() = %q, want %q", got, want)
+	}
+	if got, want := id.String(), "node//node1"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	kind, namespace, name, ok := id.Parse()
+	if !ok {
+		t.Fatal("Parse() ok = false, want true for a cluster-scoped ResourceID")
+	}
+	if kind != ResourceKindNode || namespace != "" || name != "node1" {
+		t.Errorf("Parse() = (%q, %q, %q), want (%q, %q, %q)", kind, namespace, name, ResourceKindNode, "", "node1")
+	}
+
+	parsed, ok := ParseResourceID("node//node1")
+	if !ok {
+		t.Fatal("ParseResourceID(\"node//node1\") ok = false, want true")
+	}
+	if parsed.String() != id.String() {
+		t.Errorf("ParseResourceID(\"node//node1\") = %q, want %q", parsed.String(), id.String())
+	}
+}
+
+func TestParseResourceIDMalformed(t *testing.T) {
+	cases := []string{
+		"",
+		"noseparators",
+		"kind/onlyonesep",
+		"kind/namespace/name/extra",
+		"/namespace/name",
+		"kind/namespace/",
+	}
+	for _, s := range cases {
+		if _, ok := ParseResourceID(s); ok {
+			t.Errorf("ParseResourceID(%q) ok = true, want false", s)
+		}
+	}
+}
+
+func TestParseResourceIDRoundTrip(t *testing.T) {
+	want := NewResourceID(ResourceKindEndpoint, "default", "pod-1")
+
+	got, ok := ParseResourceID(want.String())
+	if !ok {
+		t.Fatalf("ParseResourceID(%q) ok = false, want true", want.String())
+	}
+	if got.Kind() != want.Kind() || got.Namespace() != want.Namespace() || got.Name() != want.Name() {
+		t.Errorf("ParseResourceID(%q) = %+v, want %+v", want.String(), got, want)
+	}
+}
+
+func TestResourceIDJSONRoundTrip(t *testing.T) {
+	want := NewResourceID(ResourceKindCCNP, "default", "ccnp-1")
+
+	data, err := want.MarshalJSON()
+	if err != nil {
+		t.Fatalf("MarshalJSON() returned unexpected error: %v", err)
+	}
+
+	var got ResourceID
+	if err := got.UnmarshalJSON(data); err != nil {
+		t.Fatalf("UnmarshalJSON(%s) returned unexpected error: %v", data, err)
+	}
+	if got.String() != want.String() {
+		t.Errorf("UnmarshalJSON(MarshalJSON()) = %q, want %q", got.String(), want.String())
+	}
+}
+
+func TestResourceKindValidate(t *testing.T) {
+	if err := ResourceKindNode.Validate(); err != nil {
+		t.Errorf("ResourceKindNode.Validate() = %v, want nil", err)
+	}
+	if err := ResourceKind("bogus").Validate(); err == nil {
+		t.Error("ResourceKind(\"bogus\").Validate() = nil, want error")
+	}
+}